@@ -0,0 +1,284 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Это переписанный кеш: вместо одного sha256(source)[:16] в качестве
+// директории + meta.txt, используется action-cache раскладка, как в
+// cmd/go/internal/cache — запись состоит из файла действия "<hash>-a"
+// (метаданные + ожидаемый sha256 бинарника) и файла данных "<hash>-d"
+// (сам бинарник). Хеш строится из исходника, версии тулчейна, GOOS/GOARCH,
+// флагов сборки и хеша графа модулей, так что апгрейд Go или смена
+// таргета больше не используют устаревший бинарник из кеша.
+
+const (
+	cacheActionSuffix = "-a"
+	cacheDataSuffix   = "-d"
+	cacheIndexFile    = "index.json"
+
+	defaultGCMaxSize = 512 * 1024 * 1024 // 512MB
+	defaultGCMaxAge  = 30 * 24 * time.Hour
+)
+
+// cacheKeyInputs — всё, что влияет на содержимое собранного бинарника.
+type cacheKeyInputs struct {
+	Source       []byte
+	Embeds       []byte
+	GoVersion    string
+	GOOS         string
+	GOARCH       string
+	BuildFlags   string
+	ModGraphHash string
+}
+
+// computeActionHash хеширует cacheKeyInputs в hex-строку — это и есть
+// "action ID" записи кеша.
+func computeActionHash(in cacheKeyInputs) string {
+	h := sha256.New()
+	h.Write(in.Source)
+	h.Write(in.Embeds)
+	io.WriteString(h, "\x00"+in.GoVersion)
+	io.WriteString(h, "\x00"+in.GOOS)
+	io.WriteString(h, "\x00"+in.GOARCH)
+	io.WriteString(h, "\x00"+in.BuildFlags)
+	io.WriteString(h, "\x00"+in.ModGraphHash)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheEntry — содержимое файла "<hash>-a".
+type cacheEntry struct {
+	ActionHash   string    `json:"actionHash"`
+	BinarySHA256 string    `json:"binarySha256"`
+	Size         int64     `json:"size"`
+	GoVersion    string    `json:"goVersion"`
+	GOOS         string    `json:"goos"`
+	GOARCH       string    `json:"goarch"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// cacheIndexEntry отслеживает время последнего обращения для GC по LRU —
+// файловый atime ненадёжен (noatime у многих монтирований), поэтому ведём
+// его сами.
+type cacheIndexEntry struct {
+	Size       int64     `json:"size"`
+	AccessedAt time.Time `json:"accessedAt"`
+}
+
+type cacheIndex struct {
+	Entries map[string]*cacheIndexEntry `json:"entries"`
+}
+
+// moduleGraphHash hashes the resolved go.mod/go.sum of tmpDir (after
+// `go mod tidy` has run there) so a dependency upgrade changes the cache
+// key even though the script source didn't.
+func moduleGraphHash(tmpDir string) (string, error) {
+	h := sha256.New()
+	for _, name := range []string{"go.mod", "go.sum"} {
+		data, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func cacheRootDir() (string, error) {
+	root, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, "gogo"), nil
+}
+
+func actionPath(root, hash string) string {
+	return filepath.Join(root, hash+cacheActionSuffix)
+}
+
+// dataPath returns where a built binary for hash lives. goos picks the
+// binary suffix — pass the *target* GOOS, not necessarily runtime.GOOS,
+// so cross-compiled Windows binaries still get a .exe extension.
+func dataPath(root, hash, goos string) string {
+	name := hash + cacheDataSuffix
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(root, name)
+}
+
+func loadIndex(root string) *cacheIndex {
+	idx := &cacheIndex{Entries: map[string]*cacheIndexEntry{}}
+
+	data, err := os.ReadFile(filepath.Join(root, cacheIndexFile))
+	if err != nil {
+		return idx
+	}
+	json.Unmarshal(data, idx) //error
+	if idx.Entries == nil {
+		idx.Entries = map[string]*cacheIndexEntry{}
+	}
+	return idx
+}
+
+func saveIndex(root string, idx *cacheIndex) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(root, cacheIndexFile), data, 0644)
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadCacheEntry читает и проверяет запись кеша: файл данных должен
+// существовать и его sha256 должен совпадать с тем, что записан в action
+// файле. Так порченный (обрезанный, перезаписанный) бинарник не будет
+// молча запущен.
+func loadCacheEntry(root, hash string) (*cacheEntry, error) {
+	raw, err := os.ReadFile(actionPath(root, hash))
+	if err != nil {
+		return nil, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, err
+	}
+
+	sum, err := sha256File(dataPath(root, hash, entry.GOOS))
+	if err != nil {
+		return nil, err
+	}
+	if sum != entry.BinarySHA256 {
+		return nil, fmt.Errorf("cache entry %s failed integrity check", hash)
+	}
+
+	return &entry, nil
+}
+
+// storeCacheEntry записывает файл действия рядом с уже собранным по
+// dataPath(root, hash, in.GOOS) бинарником и обновляет индекс для GC.
+func storeCacheEntry(root, hash string, in cacheKeyInputs) error {
+	bin := dataPath(root, hash, in.GOOS)
+
+	sum, err := sha256File(bin)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(bin)
+	if err != nil {
+		return err
+	}
+
+	entry := cacheEntry{
+		ActionHash:   hash,
+		BinarySHA256: sum,
+		Size:         info.Size(),
+		GoVersion:    in.GoVersion,
+		GOOS:         in.GOOS,
+		GOARCH:       in.GOARCH,
+		CreatedAt:    time.Now(),
+	}
+
+	raw, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(actionPath(root, hash), raw, 0644); err != nil {
+		return err
+	}
+
+	idx := loadIndex(root)
+	idx.Entries[hash] = &cacheIndexEntry{Size: entry.Size, AccessedAt: entry.CreatedAt}
+	return saveIndex(root, idx)
+}
+
+// touchCacheEntry обновляет accessedAt записи — вызывается при попадании
+// в кеш, чтобы GC по LRU не выселял часто используемые бинарники.
+func touchCacheEntry(root, hash string, size int64) {
+	idx := loadIndex(root)
+	idx.Entries[hash] = &cacheIndexEntry{Size: size, AccessedAt: time.Now()}
+	saveIndex(root, idx) //error
+}
+
+// gcCache подрезает кеш до maxSize, выселяя по LRU (самые старые accessedAt
+// первыми), и отдельно удаляет любые записи старше maxAge.
+func gcCache(maxSize int64, maxAge time.Duration) error {
+	root, err := cacheRootDir()
+	if err != nil {
+		return err
+	}
+
+	idx := loadIndex(root)
+
+	type keyed struct {
+		hash string
+		e    *cacheIndexEntry
+	}
+	all := make([]keyed, 0, len(idx.Entries))
+	for hash, e := range idx.Entries {
+		all = append(all, keyed{hash, e})
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].e.AccessedAt.Before(all[j].e.AccessedAt)
+	})
+
+	var total int64
+	for _, k := range all {
+		total += k.e.Size
+	}
+
+	now := time.Now()
+	removed := 0
+	for _, k := range all {
+		expired := now.Sub(k.e.AccessedAt) > maxAge
+		overBudget := total > maxSize
+
+		if !expired && !overBudget {
+			continue
+		}
+
+		os.Remove(actionPath(root, k.hash))
+		// data file may carry a target-specific suffix (e.g. .exe), so
+		// glob rather than guess the GOOS it was built for.
+		if matches, err := filepath.Glob(filepath.Join(root, k.hash+cacheDataSuffix+"*")); err == nil {
+			for _, m := range matches {
+				os.Remove(m)
+			}
+		}
+		delete(idx.Entries, k.hash)
+		total -= k.e.Size
+		removed++
+	}
+
+	if removed > 0 {
+		fmt.Printf("gc: removed %d cache entries\n", removed)
+	}
+
+	return saveIndex(root, idx)
+}
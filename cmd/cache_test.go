@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestComputeActionHash(t *testing.T) {
+	base := cacheKeyInputs{
+		Source:       []byte("package main"),
+		GoVersion:    "go1.22",
+		GOOS:         "linux",
+		GOARCH:       "amd64",
+		BuildFlags:   "",
+		ModGraphHash: "abc",
+	}
+
+	if computeActionHash(base) != computeActionHash(base) {
+		t.Fatal("computeActionHash is not deterministic for identical inputs")
+	}
+
+	variants := []func(cacheKeyInputs) cacheKeyInputs{
+		func(in cacheKeyInputs) cacheKeyInputs { in.Source = []byte("package other"); return in },
+		func(in cacheKeyInputs) cacheKeyInputs { in.GoVersion = "go1.21"; return in },
+		func(in cacheKeyInputs) cacheKeyInputs { in.GOOS = "windows"; return in },
+		func(in cacheKeyInputs) cacheKeyInputs { in.GOARCH = "arm64"; return in },
+		func(in cacheKeyInputs) cacheKeyInputs { in.BuildFlags = "netgo"; return in },
+		func(in cacheKeyInputs) cacheKeyInputs { in.ModGraphHash = "def"; return in },
+	}
+
+	baseHash := computeActionHash(base)
+	for i, mutate := range variants {
+		if got := computeActionHash(mutate(base)); got == baseHash {
+			t.Errorf("variant %d: computeActionHash did not change when an input field differed", i)
+		}
+	}
+}
+
+// writeFakeEntry drops a minimal action+data pair on disk and registers it in
+// the index, mimicking what storeCacheEntry would have produced.
+func writeFakeEntry(t *testing.T, root, hash string, size int64, accessedAt time.Time) {
+	t.Helper()
+	if err := os.WriteFile(actionPath(root, hash), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dataPath(root, hash, "linux"), make([]byte, size), 0755); err != nil {
+		t.Fatal(err)
+	}
+	idx := loadIndex(root)
+	idx.Entries[hash] = &cacheIndexEntry{Size: size, AccessedAt: accessedAt}
+	if err := saveIndex(root, idx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGCCacheEvictsLRUOverBudget(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	root, err := cacheRootDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	writeFakeEntry(t, root, "oldest", 100, now.Add(-3*time.Hour))
+	writeFakeEntry(t, root, "middle", 100, now.Add(-2*time.Hour))
+	writeFakeEntry(t, root, "newest", 100, now.Add(-1*time.Hour))
+
+	// Budget only fits two of the three entries, so the LRU one must go.
+	if err := gcCache(200, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(actionPath(root, "oldest")); !os.IsNotExist(err) {
+		t.Errorf("oldest entry should have been evicted, stat err = %v", err)
+	}
+	if _, err := os.Stat(actionPath(root, "middle")); err != nil {
+		t.Errorf("middle entry should have survived, stat err = %v", err)
+	}
+	if _, err := os.Stat(actionPath(root, "newest")); err != nil {
+		t.Errorf("newest entry should have survived, stat err = %v", err)
+	}
+}
+
+func TestGCCacheEvictsExpiredRegardlessOfBudget(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	root, err := cacheRootDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	writeFakeEntry(t, root, "stale", 10, now.Add(-48*time.Hour))
+	writeFakeEntry(t, root, "fresh", 10, now.Add(-1*time.Hour))
+
+	if err := gcCache(1<<30, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(actionPath(root, "stale")); !os.IsNotExist(err) {
+		t.Errorf("stale entry should have been evicted despite budget headroom, stat err = %v", err)
+	}
+	if _, err := os.Stat(actionPath(root, "fresh")); err != nil {
+		t.Errorf("fresh entry should have survived, stat err = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, cacheIndexFile)); err != nil {
+		t.Errorf("gcCache should leave an updated index behind, stat err = %v", err)
+	}
+}
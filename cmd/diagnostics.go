@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Diagnostic — один разобранный диагностический репорт компилятора.
+// Это общее промежуточное представление: и цветной вывод для терминала,
+// и JSON-вывод для редакторов/CI строятся поверх одного и того же среза
+// Diagnostic, так что добавить ещё один рендерер (например, LSP) можно
+// не трогая parseDiagnostics.
+type Diagnostic struct {
+	File    string       `json:"file"`
+	Line    int          `json:"line"`
+	Column  int          `json:"column"`
+	Kind    string       `json:"kind"`
+	Message string       `json:"message"`
+	Context []SourceLine `json:"context,omitempty"`
+	Raw     string       `json:"raw,omitempty"`
+}
+
+// SourceLine — одна строка исходника вокруг места ошибки.
+type SourceLine struct {
+	Number  int    `json:"number"`
+	Text    string `json:"text"`
+	IsError bool   `json:"isError"`
+}
+
+// parseDiagnostics разбирает сырой вывод `go build` в список Diagnostic.
+// Строки, которые не похожи на "файл:строка:колонка: сообщение", попадают
+// в результат как Diagnostic с пустым File и заполненным Raw, чтобы ни
+// один рендерер не терял информацию. sources — исходники по базовому имени
+// файла, чтобы контекст брался из того файла, на который жалуется ошибка,
+// а не из первого попавшегося (актуально при multi-file сборках).
+func parseDiagnostics(rawOutput string, sources map[string][]byte) []Diagnostic {
+	lines := strings.Split(rawOutput, "\n")
+	diags := make([]Diagnostic, 0, len(lines))
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		matches := errorPattern.FindStringSubmatch(line)
+		if len(matches) != 5 {
+			diags = append(diags, Diagnostic{Raw: line})
+			continue
+		}
+
+		lineNum, _ := strconv.Atoi(matches[2])
+		col, _ := strconv.Atoi(matches[3])
+		kind, desc := splitErrorType(matches[4])
+
+		d := Diagnostic{
+			File:    matches[1],
+			Line:    lineNum,
+			Column:  col,
+			Kind:    kind,
+			Message: desc,
+		}
+
+		if src, ok := sources[filepath.Base(matches[1])]; ok {
+			d.Context = sourceContext(src, lineNum)
+		}
+
+		diags = append(diags, d)
+	}
+
+	return diags
+}
+
+// sourceContext возвращает по одной строке до и после errorLine (и саму строку).
+func sourceContext(source []byte, errorLine int) []SourceLine {
+	lines := strings.Split(string(source), "\n")
+	if errorLine < 1 || errorLine > len(lines) {
+		return nil
+	}
+
+	start := errorLine - 2
+	if start < 0 {
+		start = 0
+	}
+
+	end := errorLine + 1
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	ctx := make([]SourceLine, 0, end-start)
+	for i := start; i < end; i++ {
+		num := i + 1
+		ctx = append(ctx, SourceLine{
+			Number:  num,
+			Text:    lines[i],
+			IsError: num == errorLine,
+		})
+	}
+
+	return ctx
+}
+
+// renderDiagnosticsPretty — прежний цветной вывод, теперь построенный
+// поверх уже разобранных Diagnostic вместо сырых строк.
+func renderDiagnosticsPretty(diags []Diagnostic) {
+	foundErrors := false
+
+	for _, d := range diags {
+		if d.File == "" {
+			fmt.Println(d.Raw)
+			continue
+		}
+
+		foundErrors = true
+
+		errTitle.Printf("%s\n", d.Kind)
+		errFile.Printf("   %s", d.File)
+		errLine.Printf(":%d", d.Line)
+		errHint.Printf(":%d\n", d.Column)
+		errMsg.Printf("   %s\n", d.Message)
+
+		for _, sl := range d.Context {
+			if sl.IsError {
+				codeNum.Printf("  → %d | ", sl.Number)
+			} else {
+				codeNum.Printf("    %d | ", sl.Number)
+			}
+			codeLine.Println(sl.Text)
+		}
+
+		fmt.Println()
+	}
+
+	if foundErrors {
+		color.Red("\nCompilation failed")
+	}
+}
+
+// renderDiagnosticsJSON печатает один JSON-объект на строку (diags stream),
+// по аналогии с `go vet -json`/gopls, чтобы редакторы и CI могли
+// построчно читать вывод не дожидаясь его завершения.
+func renderDiagnosticsJSON(diags []Diagnostic) {
+	enc := json.NewEncoder(os.Stdout)
+	for _, d := range diags {
+		enc.Encode(d) //error
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	t.Run("matched error gets context from its own file", func(t *testing.T) {
+		sources := map[string][]byte{
+			"main.go": []byte("package main\n\nfunc main() {\n\tundefined()\n}\n"),
+		}
+		raw := "./main.go:4:2: undefined: undefined"
+
+		diags := parseDiagnostics(raw, sources)
+		if len(diags) != 1 {
+			t.Fatalf("got %d diagnostics, want 1", len(diags))
+		}
+
+		d := diags[0]
+		if d.File != "./main.go" || d.Line != 4 || d.Column != 2 {
+			t.Errorf("diag = %+v, want File=./main.go Line=4 Column=2", d)
+		}
+		if d.Kind != "undefined" || d.Message != "undefined" {
+			t.Errorf("diag kind/message = %q/%q, want undefined/undefined", d.Kind, d.Message)
+		}
+		if len(d.Context) == 0 {
+			t.Errorf("diag.Context is empty, want lines around line 4")
+		}
+	})
+
+	t.Run("unrecognized line becomes a raw diagnostic", func(t *testing.T) {
+		diags := parseDiagnostics("go: downloading example.com/foo v1.0.0", nil)
+		if len(diags) != 1 {
+			t.Fatalf("got %d diagnostics, want 1", len(diags))
+		}
+		if diags[0].File != "" || diags[0].Raw == "" {
+			t.Errorf("diag = %+v, want empty File and non-empty Raw", diags[0])
+		}
+	})
+
+	t.Run("blank lines are dropped", func(t *testing.T) {
+		diags := parseDiagnostics("\n\n   \n", nil)
+		if len(diags) != 0 {
+			t.Errorf("got %d diagnostics from blank input, want 0", len(diags))
+		}
+	})
+
+	t.Run("multiple lines preserve order", func(t *testing.T) {
+		raw := "a.go:1:1: first error\nb.go:2:2: second error"
+		diags := parseDiagnostics(raw, nil)
+		if len(diags) != 2 {
+			t.Fatalf("got %d diagnostics, want 2", len(diags))
+		}
+		if diags[0].File != "a.go" || diags[1].File != "b.go" {
+			t.Errorf("diags = %+v, want a.go then b.go", diags)
+		}
+	})
+
+	t.Run("no context when file isn't in sources", func(t *testing.T) {
+		diags := parseDiagnostics("missing.go:1:1: oops", map[string][]byte{"other.go": []byte("package main")})
+		if len(diags) != 1 {
+			t.Fatalf("got %d diagnostics, want 1", len(diags))
+		}
+		if diags[0].Context != nil {
+			t.Errorf("diags[0].Context = %v, want nil", diags[0].Context)
+		}
+	})
+}
+
+func TestSourceContext(t *testing.T) {
+	source := []byte("line1\nline2\nline3\nline4\nline5\n")
+
+	tests := []struct {
+		name      string
+		errorLine int
+		want      []SourceLine
+	}{
+		{
+			name:      "middle line gets one line of context each side",
+			errorLine: 3,
+			want: []SourceLine{
+				{Number: 2, Text: "line2", IsError: false},
+				{Number: 3, Text: "line3", IsError: true},
+				{Number: 4, Text: "line4", IsError: false},
+			},
+		},
+		{
+			name:      "first line clamps start",
+			errorLine: 1,
+			want: []SourceLine{
+				{Number: 1, Text: "line1", IsError: true},
+				{Number: 2, Text: "line2", IsError: false},
+			},
+		},
+		{
+			name:      "out of range returns nil",
+			errorLine: 99,
+			want:      nil,
+		},
+		{
+			name:      "zero is out of range",
+			errorLine: 0,
+			want:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sourceContext(source, tt.errorLine)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sourceContext(_, %d) = %+v, want %+v", tt.errorLine, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderDiagnosticsJSON(t *testing.T) {
+	diags := []Diagnostic{
+		{File: "main.go", Line: 4, Column: 2, Kind: "undefined", Message: "undefined: foo"},
+		{Raw: "go: downloading example.com/foo v1.0.0"},
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	origStdout := os.Stdout
+	os.Stdout = w
+	renderDiagnosticsJSON(diags)
+	w.Close()
+	os.Stdout = origStdout
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := json.NewDecoder(&buf)
+	var got []Diagnostic
+	for dec.More() {
+		var d Diagnostic
+		if err := dec.Decode(&d); err != nil {
+			t.Fatalf("decoding emitted JSON: %v", err)
+		}
+		got = append(got, d)
+	}
+
+	if !reflect.DeepEqual(got, diags) {
+		t.Errorf("renderDiagnosticsJSON round-trip = %+v, want %+v", got, diags)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Директивы вида "//gogo:require ..." в заголовке скрипта позволяют
+// закрепить версии зависимостей прямо в исходнике — иначе `go mod tidy`
+// на первом запуске подхватывает произвольные последние версии и сборка
+// не воспроизводится на другой машине.
+
+type requireDirective struct {
+	Path    string
+	Version string
+}
+
+type replaceDirective struct {
+	Old        string
+	OldVersion string
+	New        string
+	NewVersion string
+}
+
+type excludeDirective struct {
+	Path    string
+	Version string
+}
+
+// pragmas — все директивы, найденные в заголовке одного файла.
+type pragmas struct {
+	Embeds   []string
+	Requires []requireDirective
+	Replaces []replaceDirective
+	Excludes []excludeDirective
+}
+
+var (
+	requirePragmaRe = regexp.MustCompile(`^//gogo:require\s+(\S+)\s+(\S+)\s*$`)
+	replacePragmaRe = regexp.MustCompile(`^//gogo:replace\s+(.+?)\s*=>\s*(.+?)\s*$`)
+	excludePragmaRe = regexp.MustCompile(`^//gogo:exclude\s+(\S+)\s+(\S+)\s*$`)
+)
+
+// parsePragmas scans the leading comment block of a source file (the same
+// header region validateCode scans for the package clause) for gogo's
+// directive comments and returns everything it found.
+func parsePragmas(code []byte) (pragmas, error) {
+	var p pragmas
+
+	scanner := bufio.NewScanner(bytes.NewReader(code))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "//gogo:embed"):
+			if m := embedPragmaRe.FindStringSubmatch(line); m != nil {
+				p.Embeds = append(p.Embeds, strings.TrimSpace(m[1]))
+			}
+		case strings.HasPrefix(line, "//gogo:require"):
+			m := requirePragmaRe.FindStringSubmatch(line)
+			if m == nil {
+				return p, fmt.Errorf("malformed directive: %s (want //gogo:require <module> <version>)", line)
+			}
+			p.Requires = append(p.Requires, requireDirective{Path: m[1], Version: m[2]})
+		case strings.HasPrefix(line, "//gogo:replace"):
+			m := replacePragmaRe.FindStringSubmatch(line)
+			if m == nil {
+				return p, fmt.Errorf("malformed directive: %s (want //gogo:replace <old> => <new> [version])", line)
+			}
+			r := replaceDirective{}
+			r.Old, r.OldVersion = splitModuleVersion(m[1])
+			r.New, r.NewVersion = splitModuleVersion(m[2])
+			p.Replaces = append(p.Replaces, r)
+		case strings.HasPrefix(line, "//gogo:exclude"):
+			m := excludePragmaRe.FindStringSubmatch(line)
+			if m == nil {
+				return p, fmt.Errorf("malformed directive: %s (want //gogo:exclude <module> <version>)", line)
+			}
+			p.Excludes = append(p.Excludes, excludeDirective{Path: m[1], Version: m[2]})
+		case strings.HasPrefix(line, "//"):
+			// обычный комментарий — пропускаем
+		default:
+			return p, nil
+		}
+	}
+
+	return p, nil
+}
+
+// splitModuleVersion splits "module v1.2.3" into its two fields; a local
+// replace target ("../foo") has no version and is returned as-is.
+func splitModuleVersion(s string) (path, version string) {
+	fields := strings.Fields(s)
+	if len(fields) == 2 {
+		return fields[0], fields[1]
+	}
+	return s, ""
+}
+
+// renderGoMod builds a go.mod that pins every //gogo:require/replace/exclude
+// directive so `go mod tidy` reproduces the same dependency graph on every
+// machine instead of resolving arbitrary latest versions.
+func renderGoMod(goVersion string, p pragmas) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "module gogo-main\n\ngo %s\n", goVersion)
+
+	if len(p.Requires) > 0 {
+		b.WriteString("\nrequire (\n")
+		for _, r := range p.Requires {
+			fmt.Fprintf(&b, "\t%s %s\n", r.Path, r.Version)
+		}
+		b.WriteString(")\n")
+	}
+
+	for _, r := range p.Replaces {
+		b.WriteString("\nreplace ")
+		b.WriteString(r.Old)
+		if r.OldVersion != "" {
+			fmt.Fprintf(&b, " %s", r.OldVersion)
+		}
+		fmt.Fprintf(&b, " => %s", r.New)
+		if r.NewVersion != "" {
+			fmt.Fprintf(&b, " %s", r.NewVersion)
+		}
+		b.WriteString("\n")
+	}
+
+	for _, e := range p.Excludes {
+		fmt.Fprintf(&b, "\nexclude %s %s\n", e.Path, e.Version)
+	}
+
+	return b.String()
+}
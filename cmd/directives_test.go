@@ -0,0 +1,160 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitModuleVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		wantPath    string
+		wantVersion string
+	}{
+		{"path and version", "example.com/foo v1.2.3", "example.com/foo", "v1.2.3"},
+		{"local replace target", "../foo", "../foo", ""},
+		{"single word", "example.com/foo", "example.com/foo", ""},
+		{"extra whitespace collapses to two fields", "  example.com/foo   v1.2.3  ", "example.com/foo", "v1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, version := splitModuleVersion(tt.in)
+			if path != tt.wantPath || version != tt.wantVersion {
+				t.Errorf("splitModuleVersion(%q) = (%q, %q), want (%q, %q)", tt.in, path, version, tt.wantPath, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestParsePragmas(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		want    pragmas
+		wantErr bool
+	}{
+		{
+			name: "embed",
+			code: "//gogo:embed assets/*.txt\npackage main\n",
+			want: pragmas{Embeds: []string{"assets/*.txt"}},
+		},
+		{
+			name: "require",
+			code: "//gogo:require example.com/foo v1.2.3\npackage main\n",
+			want: pragmas{Requires: []requireDirective{{Path: "example.com/foo", Version: "v1.2.3"}}},
+		},
+		{
+			name: "replace with version",
+			code: "//gogo:replace example.com/foo => example.com/bar v1.0.0\npackage main\n",
+			want: pragmas{Replaces: []replaceDirective{{Old: "example.com/foo", New: "example.com/bar", NewVersion: "v1.0.0"}}},
+		},
+		{
+			name: "replace local path has no version",
+			code: "//gogo:replace example.com/foo => ../bar\npackage main\n",
+			want: pragmas{Replaces: []replaceDirective{{Old: "example.com/foo", New: "../bar"}}},
+		},
+		{
+			name: "replace with pinned old version",
+			code: "//gogo:replace example.com/foo v1.0.0 => ../vendor/foo\npackage main\n",
+			want: pragmas{Replaces: []replaceDirective{{Old: "example.com/foo", OldVersion: "v1.0.0", New: "../vendor/foo"}}},
+		},
+		{
+			name: "exclude",
+			code: "//gogo:exclude example.com/foo v1.2.3\npackage main\n",
+			want: pragmas{Excludes: []excludeDirective{{Path: "example.com/foo", Version: "v1.2.3"}}},
+		},
+		{
+			name: "plain comments are skipped",
+			code: "// just a comment\n//gogo:embed data.txt\npackage main\n",
+			want: pragmas{Embeds: []string{"data.txt"}},
+		},
+		{
+			name: "stops at first non-comment line",
+			code: "//gogo:embed before.txt\npackage main\n//gogo:embed after.txt\n",
+			want: pragmas{Embeds: []string{"before.txt"}},
+		},
+		{
+			name:    "malformed require",
+			code:    "//gogo:require example.com/foo\npackage main\n",
+			wantErr: true,
+		},
+		{
+			name:    "malformed replace",
+			code:    "//gogo:replace example.com/foo\npackage main\n",
+			wantErr: true,
+		},
+		{
+			name:    "malformed exclude",
+			code:    "//gogo:exclude example.com/foo\npackage main\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePragmas([]byte(tt.code))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parsePragmas(%q) = nil error, want error", tt.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePragmas(%q) unexpected error: %v", tt.code, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePragmas(%q) = %+v, want %+v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderGoMod(t *testing.T) {
+	tests := []struct {
+		name string
+		p    pragmas
+		want string
+	}{
+		{
+			name: "require",
+			p:    pragmas{Requires: []requireDirective{{Path: "example.com/foo", Version: "v1.2.3"}}},
+			want: "module gogo-main\n\ngo 1.22\n\nrequire (\n\texample.com/foo v1.2.3\n)\n",
+		},
+		{
+			name: "replace without versions",
+			p:    pragmas{Replaces: []replaceDirective{{Old: "example.com/foo", New: "../bar"}}},
+			want: "module gogo-main\n\ngo 1.22\n\nreplace example.com/foo => ../bar\n",
+		},
+		{
+			name: "replace with new version only",
+			p:    pragmas{Replaces: []replaceDirective{{Old: "example.com/foo", New: "example.com/bar", NewVersion: "v1.0.0"}}},
+			want: "module gogo-main\n\ngo 1.22\n\nreplace example.com/foo => example.com/bar v1.0.0\n",
+		},
+		{
+			name: "replace pins the old version too",
+			p:    pragmas{Replaces: []replaceDirective{{Old: "example.com/foo", OldVersion: "v1.0.0", New: "../vendor/foo"}}},
+			want: "module gogo-main\n\ngo 1.22\n\nreplace example.com/foo v1.0.0 => ../vendor/foo\n",
+		},
+		{
+			name: "replace pins both versions",
+			p:    pragmas{Replaces: []replaceDirective{{Old: "example.com/foo", OldVersion: "v1.0.0", New: "example.com/bar", NewVersion: "v2.0.0"}}},
+			want: "module gogo-main\n\ngo 1.22\n\nreplace example.com/foo v1.0.0 => example.com/bar v2.0.0\n",
+		},
+		{
+			name: "exclude",
+			p:    pragmas{Excludes: []excludeDirective{{Path: "example.com/foo", Version: "v1.2.3"}}},
+			want: "module gogo-main\n\ngo 1.22\n\nexclude example.com/foo v1.2.3\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderGoMod("1.22", tt.p)
+			if got != tt.want {
+				t.Errorf("renderGoMod(%+v) = %q, want %q", tt.p, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,21 +1,15 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/fatih/color"
 )
@@ -24,6 +18,15 @@ var (
 	noCache     = flag.Bool("once", false, "Disable cache (compile and run without saving)")
 	clearCache  = flag.Bool("clear", false, "Remove all cached binaries")
 	showVersion = flag.Bool("ver", false, "Show version")
+	jsonOutput  = flag.Bool("json", false, "Emit compiler diagnostics as a stream of JSON objects")
+	runGC       = flag.Bool("gc", false, "Trim the cache to -gc-size/-gc-age and exit")
+	gcMaxSize   = flag.Int64("gc-size", defaultGCMaxSize, "Cache size budget in bytes for -gc")
+	gcMaxAge    = flag.Duration("gc-age", defaultGCMaxAge, "Cache entry age budget for -gc")
+	targetFlag  = flag.String("target", "", "Cross-compile target as GOOS/GOARCH (e.g. linux/amd64), defaults to the host")
+	tagsFlag    = flag.String("tags", "", "Build tags passed to `go build -tags`")
+	outputPath  = flag.String("o", "", "Also copy the built binary to this path (not used with -once)")
+	watch       = flag.Bool("watch", false, "Rebuild and rerun on every change to the input file(s)")
+	restartSig  = flag.String("restart-signal", "TERM", "Signal sent to the running child before a -watch restart (TERM, INT, HUP, KILL, QUIT)")
 
 	errTitle    = color.New(color.FgRed, color.Bold, color.Underline)
 	errFile     = color.New(color.FgCyan)
@@ -41,49 +44,6 @@ func printErrWithColor(c *color.Color, format string, args ...any) {
 	c.Fprintf(os.Stderr, format+"\n", args...)
 }
 
-func parseGoErrors(rawOutput string, sourceCode []byte) {
-	lines := strings.Split(rawOutput, "\n")
-	foundErrors := false
-	
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		matches := errorPattern.FindStringSubmatch(line)
-		if len(matches) != 5 {
-			// Не распарсилось — выводим как есть
-			fmt.Println(line)
-			continue
-		}
-
-		foundErrors = true
-		file := matches[1]
-		lineNum, _ := strconv.Atoi(matches[2])
-		col := matches[3]
-		message := matches[4]
-
-		errType, description := splitErrorType(message)
-
-		errTitle.Printf("%s\n", errType)
-		errFile.Printf("   %s", file)
-		errLine.Printf(":%d", lineNum)
-		errHint.Printf(":%s\n", col)
-		errMsg.Printf("   %s\n", description)
-
-		if len(sourceCode) > 0 {
-			showSourceContext(sourceCode, lineNum)
-		}
-
-		fmt.Println()	
-	}
-
-	if foundErrors {
-		color.Red("\nCompilation failed")
-	}
-}
-
 func splitErrorType(msg string) (typ, desc string) {
 	if  idx := strings.Index(msg, ":"); idx > 0 {
 		possibleType := msg[:idx]
@@ -96,40 +56,19 @@ func splitErrorType(msg string) (typ, desc string) {
 	return "error", msg
 }
 
-func showSourceContext(source []byte, errorLine int) {
-	lines := strings.Split(string(source), "\n")
-	if errorLine < 1 || errorLine > len(lines) {
-		return
-	}
-
-	start := errorLine - 2
-	if start < 0 {
-		start = 0
-	}
-
-	end := errorLine + 1
-	if end > len(lines) {
-		end = len(lines)
-	}
+func buildWith(tmpDir, binaryPath string, sources map[string][]byte, target buildTarget) error {
+	var stderr bytes.Buffer
 
-	for i := start; i < end; i++ {
-		num := i + 1
-		if num == errorLine {
-			codeNum.Printf("  → %d | ", num)
-			codeLine.Println(lines[i])
-		} else {
-			codeNum.Printf("    %d | ", num)
-			codeLine.Println(lines[i])
-		}
+	args := []string{"build", "-o", binaryPath}
+	if target.Tags != "" {
+		args = append(args, "-tags", target.Tags)
 	}
-}
+	args = append(args, ".")
 
-func buildWith(tmpDir, binaryPath string, sourceCode []byte) error {
-	var stderr bytes.Buffer
-
-	cmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	cmd := exec.Command("go", args...)
 	cmd.Dir = tmpDir
 	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "GOOS="+target.GOOS, "GOARCH="+target.GOARCH)
 
 	if err := cmd.Run(); err != nil {
 		output := stderr.String()
@@ -138,7 +77,12 @@ func buildWith(tmpDir, binaryPath string, sourceCode []byte) error {
 			return fmt.Errorf("build failed: %v", err)
 		}
 
-		parseGoErrors(output, sourceCode)
+		diags := parseDiagnostics(output, sources)
+		if *jsonOutput {
+			renderDiagnosticsJSON(diags)
+		} else {
+			renderDiagnosticsPretty(diags)
+		}
 		return fmt.Errorf("compilation failed")
 
 	}
@@ -146,42 +90,13 @@ func buildWith(tmpDir, binaryPath string, sourceCode []byte) error {
 	return nil
 }
 
-func getCachePaths(code []byte) (dir, binaryPath string) {
-	hash := sha256.Sum256(code)
-	hashStr := hex.EncodeToString(hash[:])[:16]
-
-	// ~/.cache/gogo/ на Unix, %LOCALAPPDATA%\gogo\ на Windows
-	casheRoot, err := os.UserCacheDir() // error
-	if err != nil {
-		return "", ""
-	}
-	dir = filepath.Join(casheRoot, "gogo", hashStr)
-
-	binName := "run"
-	if runtime.GOOS == "windows" {
-		binName = "run.exe"
-	}
-
-	binaryPath = filepath.Join(dir, binName)
-
-	return 
-}
-
-func validateCode(code []byte) error {
-	scanner := bufio.NewScanner(bytes.NewReader(code))
-	for scanner.Scan() {
-		line := strings.TrimSpace((scanner.Text()))
-		if line == "" || strings.HasPrefix(line, "//") {
-			continue
-		} 
-		
-		if strings.HasPrefix(line, "package ") {
-			return nil
-		}
-		break
+func goShortVersion() string {
+	goVersion := strings.TrimPrefix(runtime.Version(), "go")
+	parts := strings.Split(goVersion, ".")
+	if len(parts) >= 2 {
+		goVersion = parts[0] + "." + parts[1]
 	}
-
-	return fmt.Errorf("code must contain \"package <name>\"")
+	return goVersion
 }
 
 func stripShebang(code []byte) ([]byte, error) {
@@ -197,108 +112,156 @@ func stripShebang(code []byte) ([]byte, error) {
 	return code, nil
 }
 
-func readInput(filename string) ([]byte, error) {
-	if filename != "" {
-		return os.ReadFile(filename)
+// isStdinTTY сообщает, подключён ли stdin к терминалу, а не к пайпу/файлу
+// — используется, чтобы решить, печатать ли подсказку по использованию.
+func isStdinTTY() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
 	}
-	return io.ReadAll(os.Stdin)
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
 func clearAllCache() error {
-	cacheRoot, err := os.UserCacheDir()
+	root, err := cacheRootDir()
 	if err != nil {
 		return err
 	}
-
-	gogoCache := filepath.Join(cacheRoot, "gogo")
-	return os.RemoveAll(gogoCache)
+	return os.RemoveAll(root)
 }
 
-// createModule создаёт go.mod и main.go во временной директории.
-func createModule(dir string, code []byte) error {
-	// Версия Go
-	goVersion := runtime.Version()
-	goVersion = strings.TrimPrefix(goVersion, "go")
-	parts := strings.Split(goVersion, ".")
-	if len(parts) >= 2 {
-		goVersion = parts[0] + "." + parts[1]
-	}
-
-	// go.mod
-	modContent := fmt.Sprintf("module gogo-main\n\ngo %s\n", goVersion)
+// createModule создаёт go.mod (с учётом //gogo:require/replace/exclude) и
+// раскладывает исходники sourceSet во временной директории (см.
+// writeModuleFiles).
+func createModule(dir string, s *sourceSet) error {
+	modContent := renderGoMod(goShortVersion(), s.Pragmas)
 	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(modContent), 0644); err != nil {
 		return err
 	}
 
-	// main.go
-	return os.WriteFile(filepath.Join(dir, "main.go"), code, 0644)
+	return writeModuleFiles(dir, s)
 }
 
-// runCached: с кешем (по умолчанию).
-func runCached(code []byte) error {
-	cacheDir, binaryPath := getCachePaths(code)
-
-	if info, err := os.Stat(binaryPath); err == nil {
-		if time.Since(info.ModTime()) < 3*24*time.Hour {
-			color.Yellow("Using cached binary")
-			return runBinary(binaryPath)
-		}
-	}
-
-	// Создаём директорию кеша
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		return err
-	}
-
-	// Сохраняем исходник для отладки
-	sourcePath := filepath.Join(cacheDir, "main.go")
-	err := os.WriteFile(sourcePath, code, 0644)
+// buildCached — действие-кеш lookup: исходник сначала собирается во
+// временном модуле, чтобы получить актуальный граф зависимостей, а уже по
+// (исходник, версия Go, GOOS/GOARCH, tags, граф модулей) строится action
+// hash записи. Возвращает путь к готовому (кешированному или только что
+// собранному) бинарнику, не запуская его — так buildCached годится и для
+// runCached, и для -watch, которому нужно управлять жизнью процесса самому.
+func buildCached(s *sourceSet, target buildTarget) (string, error) {
+	root, err := cacheRootDir()
 	if err != nil {
-		return err
+		return "", err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", err
 	}
 
-	// Компиляция во временной папке
 	tmpDir, err := os.MkdirTemp("", "gogo-build-*")
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer os.RemoveAll(tmpDir) // error
 
-	if err := createModule(tmpDir, code); err != nil {
-		return err
+	if err := createModule(tmpDir, s); err != nil {
+		return "", err
 	}
 
-	// go mod tidy
 	tidy := exec.Command("go", "mod", "tidy")
 	tidy.Dir = tmpDir
 	tidy.Stderr = os.Stderr
-	color.Yellow("First run: downloading dependencies...")
+	color.Yellow("Resolving dependencies...")
 	if err := tidy.Run(); err != nil {
-		return fmt.Errorf("go mod tidy failed: %w", err)
+		return "", fmt.Errorf("go mod tidy failed: %w", err)
+	}
+
+	modHash, err := moduleGraphHash(tmpDir)
+	if err != nil {
+		return "", err
+	}
+
+	embeds, err := s.embedContent()
+	if err != nil {
+		return "", err
+	}
+
+	keyInputs := cacheKeyInputs{
+		Source:       s.concat(),
+		Embeds:       embeds,
+		GoVersion:    runtime.Version(),
+		GOOS:         target.GOOS,
+		GOARCH:       target.GOARCH,
+		BuildFlags:   target.Tags,
+		ModGraphHash: modHash,
+	}
+	hash := computeActionHash(keyInputs)
+	binaryPath := dataPath(root, hash, target.GOOS)
+
+	if entry, err := loadCacheEntry(root, hash); err == nil {
+		color.Yellow("Using cached binary")
+		touchCacheEntry(root, hash, entry.Size)
+		return binaryPath, nil
 	}
 
 	color.Yellow("Building...")
 
-if err := buildWith(tmpDir, binaryPath, code); err != nil {
-    os.RemoveAll(cacheDir)
-    return err
-}
+	if err := buildWith(tmpDir, binaryPath, s.sourcesByName(), target); err != nil {
+		os.Remove(binaryPath)
+		return "", err
+	}
 
-	if runtime.GOOS != "windows" {
+	if target.GOOS != "windows" {
 		os.Chmod(binaryPath, 0755) //error
 	}
 
-	meta := fmt.Sprintf("Built: %s\nGo: %s\n", 
-        time.Now().Format(time.RFC3339), 
-        runtime.Version())
-	
-    os.WriteFile(filepath.Join(cacheDir, "meta.txt"), []byte(meta), 0644) //error
+	if err := storeCacheEntry(root, hash, keyInputs); err != nil {
+		return "", err
+	}
+
+	return binaryPath, nil
+}
+
+// runCached: с кешем (по умолчанию, без -watch).
+func runCached(s *sourceSet, target buildTarget) error {
+	binaryPath, err := buildCached(s, target)
+	if err != nil {
+		return err
+	}
+	return finishBuild(binaryPath, target)
+}
+
+// copyToOutput copies the built binary to -o, if given. Shared by finishBuild
+// (the synchronous default cached path) and -watch's rebuild loop, so -o
+// behaves the same whichever path produced the binary.
+func copyToOutput(binaryPath string) error {
+	if *outputPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(binaryPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*outputPath, data, 0755)
+}
+
+// finishBuild first copies the binary to -o if given, then either runs the
+// freshly built/cached binary (host target) or, for a cross-compiled target,
+// prints the cache path, since there's nothing to exec on this host.
+func finishBuild(binaryPath string, target buildTarget) error {
+	if err := copyToOutput(binaryPath); err != nil {
+		return err
+	}
+
+	if target.isCrossCompile() {
+		fmt.Println(binaryPath)
+		return nil
+	}
 
 	return runBinary(binaryPath)
 }
 
 // runOnce: без кеша (для -once флага).
-func runOnce(code []byte) error {
+func runOnce(s *sourceSet) error {
 	tmpDir, err := os.MkdirTemp("", "gogo-once-*")
 	if err != nil {
 		return err
@@ -306,7 +269,7 @@ func runOnce(code []byte) error {
 
 	defer os.RemoveAll(tmpDir) // error
 
-	if err := createModule(tmpDir, code); err != nil {
+	if err := createModule(tmpDir, s); err != nil {
 		return err
 	}
 
@@ -356,45 +319,70 @@ func main() {
         return
 	}
 
-	code, err := readInput(flag.Arg(0))
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Read error: %v\n", err)
-		os.Exit(1)
-	}
-
-	if *noCache {
-		err = runOnce(code)
-	} else {
-		err = runCached(code)
+	if *runGC {
+		if err := gcCache(*gcMaxSize, *gcMaxAge); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to gc cache: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
-    
-	if err != nil {
-        fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-        os.Exit(1)
-    }
 
-    if len(code) == 0 {
+	if flag.NArg() == 0 && isStdinTTY() {
         fmt.Fprintln(os.Stderr, "Usage: echo 'code' | gogo")
 		fmt.Fprintln(os.Stderr, "       gogo file.go")
-		fmt.Fprintln(os.Stderr, "Flags: -once (no cache), -clear (clean cache), -version")
+		fmt.Fprintln(os.Stderr, "       gogo file1.go file2.go ...")
+		fmt.Fprintln(os.Stderr, "       gogo ./dir")
+		fmt.Fprintln(os.Stderr, "Flags: -once (no cache), -clear (clean cache), -gc (trim cache), -json (structured diagnostics),")
+		fmt.Fprintln(os.Stderr, "       -target GOOS/GOARCH (cross-compile), -tags, -o (copy binary out),")
+		fmt.Fprintln(os.Stderr, "       -watch (rebuild on change), -restart-signal, -ver")
 		os.Exit(1)
         return
     }
-    
-	if err = validateCode(code); err != nil {
-		fmt.Fprintf(os.Stderr, "Error validation: %v\n", err)
+
+	target, err := resolveTarget(*targetFlag, *tagsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *noCache && target.isCrossCompile() {
+		fmt.Fprintln(os.Stderr, "Error: -target cross-compilation requires the cache (can't use with -once)")
+		os.Exit(1)
+	}
+
+	if *noCache && *watch {
+		fmt.Fprintln(os.Stderr, "Error: -watch requires the cache (can't use with -once)")
+		os.Exit(1)
+	}
+
+	if *watch && target.isCrossCompile() {
+		fmt.Fprintln(os.Stderr, "Error: -watch can't run a cross-compiled -target (nothing to exec on this host)")
 		os.Exit(1)
 	}
 
-	if code, err = stripShebang(code); err != nil {
-		fmt.Fprintf(os.Stderr, "Error shebang: %v\n", err)
+	if *watch {
+		sig, err := parseRestartSignal(*restartSig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := runWatch(flag.Args(), target, sig); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	s, err := collectSource(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
 	if *noCache {
-		err = runOnce(code)
+		err = runOnce(s)
 	} else {
-		err = runCached(code)
+		err = runCached(s, target)
 	}
 
 	if err != nil {
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// sourceFile — один .go файл, который поедет во временный модуль.
+// Name хранит путь относительно корня модуля (обычно просто базовое имя).
+type sourceFile struct {
+	Name string
+	Data []byte
+}
+
+// sourceSet — весь ввод одного запуска gogo: один файл (stdin или один
+// аргумент), несколько файлов через аргументы командной строки, либо все
+// .go файлы каталога.
+type sourceSet struct {
+	Files   []sourceFile
+	Package string
+	BaseDir string // откуда резолвить //gogo:embed
+	Pragmas pragmas
+}
+
+var (
+	packageLineRe = regexp.MustCompile(`^package\s+(\w+)`)
+	embedPragmaRe = regexp.MustCompile(`^//gogo:embed\s+(.+)$`)
+	runFuncRe     = regexp.MustCompile(`(?m)^func\s+Run\s*\(`)
+)
+
+// collectSource собирает sourceSet из аргументов командной строки:
+//   - нет аргументов: читаем stdin как один файл main.go
+//   - один аргумент, являющийся каталогом: все *.go файлы каталога
+//   - один или несколько аргументов-файлов: читаем их все как есть
+func collectSource(args []string) (*sourceSet, error) {
+	if len(args) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return newSourceSet([]sourceFile{{Name: "main.go", Data: data}}, "")
+	}
+
+	if len(args) == 1 {
+		if info, err := os.Stat(args[0]); err == nil && info.IsDir() {
+			matches, err := filepath.Glob(filepath.Join(args[0], "*.go"))
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no .go files found in %s", args[0])
+			}
+			sort.Strings(matches)
+
+			files := make([]sourceFile, 0, len(matches))
+			for _, m := range matches {
+				data, err := os.ReadFile(m)
+				if err != nil {
+					return nil, err
+				}
+				files = append(files, sourceFile{Name: filepath.Base(m), Data: data})
+			}
+			return newSourceSet(files, args[0])
+		}
+	}
+
+	files := make([]sourceFile, 0, len(args))
+	for _, a := range args {
+		data, err := os.ReadFile(a)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sourceFile{Name: filepath.Base(a), Data: data})
+	}
+	return newSourceSet(files, filepath.Dir(args[0]))
+}
+
+// newSourceSet strips shebangs, detects the package name from the first
+// file and pulls //gogo:embed globs out of its header comments.
+func newSourceSet(files []sourceFile, baseDir string) (*sourceSet, error) {
+	for i, f := range files {
+		stripped, err := stripShebang(f.Data)
+		if err != nil {
+			return nil, err
+		}
+		files[i].Data = stripped
+	}
+
+	if len(files) == 0 {
+		return &sourceSet{BaseDir: baseDir}, nil
+	}
+
+	pkg, err := validateCode(files[0].Data)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := parsePragmas(files[0].Data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sourceSet{
+		Files:   files,
+		Package: pkg,
+		BaseDir: baseDir,
+		Pragmas: p,
+	}, nil
+}
+
+// validateCode reads the leading comment/blank lines of a source file
+// looking for its "package <name>" clause and returns that name. Any
+// package name is accepted now, not just "main" — see writeModuleFiles.
+func validateCode(code []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(code))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if m := packageLineRe.FindStringSubmatch(line); m != nil {
+			return m[1], nil
+		}
+		break
+	}
+	return "", fmt.Errorf("code must contain \"package <name>\"")
+}
+
+// sourcesByName indexes a sourceSet's files by base name, for looking up
+// diagnostic context by the filename the Go compiler reports.
+func (s *sourceSet) sourcesByName() map[string][]byte {
+	m := make(map[string][]byte, len(s.Files))
+	for _, f := range s.Files {
+		m[f.Name] = f.Data
+	}
+	return m
+}
+
+// concat deterministically joins every file's name and content, used as
+// the cache key's source input so any file change busts the cache.
+func (s *sourceSet) concat() []byte {
+	var buf bytes.Buffer
+	for _, f := range s.Files {
+		buf.WriteString(f.Name)
+		buf.WriteByte(0)
+		buf.Write(f.Data)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// writeModuleFiles lays sourceSet's files (and any //gogo:embed matches)
+// out under dir. Package-main sources go straight into dir so `go build .`
+// picks them up; anything else is nested under dir/pkgsrc and dir/main.go
+// is synthesized to call its exported Run().
+func writeModuleFiles(dir string, s *sourceSet) error {
+	codeDir := dir
+	if s.Package != "main" {
+		codeDir = filepath.Join(dir, "pkgsrc")
+		if err := os.MkdirAll(codeDir, 0755); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range s.Files {
+		if err := os.WriteFile(filepath.Join(codeDir, f.Name), f.Data, 0644); err != nil {
+			return err
+		}
+	}
+
+	if err := copyEmbeds(codeDir, s); err != nil {
+		return err
+	}
+
+	if s.Package == "main" {
+		return nil
+	}
+
+	hasRunFunc := false
+	for _, f := range s.Files {
+		if runFuncRe.Match(f.Data) {
+			hasRunFunc = true
+			break
+		}
+	}
+	if !hasRunFunc {
+		return fmt.Errorf("package %q must export a Run() function to be run by gogo", s.Package)
+	}
+
+	wrapper := fmt.Sprintf("package main\n\nimport \"gogo-main/pkgsrc\"\n\nfunc main() {\n\t%s.Run()\n}\n", s.Package)
+	return os.WriteFile(filepath.Join(dir, "main.go"), []byte(wrapper), 0644)
+}
+
+// resolveEmbeds expands every //gogo:embed glob against s.BaseDir into a
+// flat, deterministically ordered (sorted per glob) list of matched paths —
+// shared by copyEmbeds and embedContent so both agree on what "the embeds"
+// are.
+func (s *sourceSet) resolveEmbeds() ([]string, error) {
+	var all []string
+	for _, glob := range s.Pragmas.Embeds {
+		pattern := glob
+		if s.BaseDir != "" && !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(s.BaseDir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("gogo:embed %q: %w", glob, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("gogo:embed %q matched no files", glob)
+		}
+		sort.Strings(matches)
+		all = append(all, matches...)
+	}
+	return all, nil
+}
+
+// embedContent deterministically joins every //gogo:embed match's path and
+// content, for folding into the cache key — otherwise editing an embedded
+// file wouldn't bust a binary cached from the same unchanged script.
+func (s *sourceSet) embedContent() ([]byte, error) {
+	matches, err := s.resolveEmbeds()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, m := range matches {
+		data, err := os.ReadFile(m)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(m)
+		buf.WriteByte(0)
+		buf.Write(data)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes(), nil
+}
+
+// copyEmbeds resolves each //gogo:embed glob against s.BaseDir and copies
+// matches into codeDir, next to the script's own source files, so a
+// //go:embed directive in the script sees them. Each match is written under
+// its base name; the directory part of the glob is not kept.
+func copyEmbeds(codeDir string, s *sourceSet) error {
+	matches, err := s.resolveEmbeds()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range matches {
+		data, err := os.ReadFile(m)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(codeDir, filepath.Base(m)), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
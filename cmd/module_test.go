@@ -0,0 +1,155 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollectSourceSingleFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hello.go")
+	writeFile(t, path, "package main\n\nfunc main() {}\n")
+
+	s, err := collectSource([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Files) != 1 || s.Files[0].Name != "hello.go" {
+		t.Errorf("collectSource(%q).Files = %+v, want one file named hello.go", path, s.Files)
+	}
+	if s.Package != "main" {
+		t.Errorf("Package = %q, want main", s.Package)
+	}
+}
+
+func TestCollectSourceDirectorySortsFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "b.go"), "package foo\n\nfunc Run() {}\n")
+	writeFile(t, filepath.Join(dir, "a.go"), "package foo\n\nfunc helper() {}\n")
+
+	s, err := collectSource([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Files) != 2 || s.Files[0].Name != "a.go" || s.Files[1].Name != "b.go" {
+		t.Fatalf("collectSource(%q).Files = %+v, want [a.go b.go] in that order", dir, s.Files)
+	}
+	if s.Package != "foo" {
+		t.Errorf("Package = %q, want foo", s.Package)
+	}
+}
+
+func TestCollectSourceEmptyDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := collectSource([]string{dir}); err == nil {
+		t.Fatal("collectSource on an empty directory = nil error, want error")
+	}
+}
+
+func TestWriteModuleFilesMainPackage(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSourceSet([]sourceFile{{Name: "main.go", Data: []byte("package main\n\nfunc main() {}\n")}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeModuleFiles(dir, s); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main.go")); err != nil {
+		t.Errorf("main.go not written directly into dir: %v", err)
+	}
+}
+
+func TestWriteModuleFilesNonMainWithoutRunFuncFails(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSourceSet([]sourceFile{{Name: "lib.go", Data: []byte("package foo\n\nfunc helper() {}\n")}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeModuleFiles(dir, s); err == nil {
+		t.Fatal("writeModuleFiles for a package without Run() = nil error, want error")
+	}
+}
+
+func TestWriteModuleFilesFindsRunFuncInAnyFile(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSourceSet([]sourceFile{
+		{Name: "a.go", Data: []byte("package foo\n\nfunc helper() {}\n")},
+		{Name: "b.go", Data: []byte("package foo\n\nfunc Run() {}\n")},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeModuleFiles(dir, s); err != nil {
+		t.Fatalf("writeModuleFiles with Run() in a non-first file: %v", err)
+	}
+
+	wrapper, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(wrapper), "foo.Run()") {
+		t.Errorf("generated wrapper %q doesn't call foo.Run()", wrapper)
+	}
+
+	for _, name := range []string{"a.go", "b.go"} {
+		if _, err := os.Stat(filepath.Join(dir, "pkgsrc", name)); err != nil {
+			t.Errorf("%s not written under pkgsrc: %v", name, err)
+		}
+	}
+}
+
+func TestCopyEmbedsAndEmbedContent(t *testing.T) {
+	baseDir := t.TempDir()
+	writeFile(t, filepath.Join(baseDir, "data.txt"), "version1")
+
+	s := &sourceSet{
+		BaseDir: baseDir,
+		Pragmas: pragmas{Embeds: []string{"data.txt"}},
+	}
+
+	codeDir := t.TempDir()
+	if err := copyEmbeds(codeDir, s); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(codeDir, "data.txt")); err != nil {
+		t.Errorf("data.txt not copied into codeDir: %v", err)
+	}
+
+	before, err := s.embedContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, filepath.Join(baseDir, "data.txt"), "version2-changed")
+	after, err := s.embedContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(before) == string(after) {
+		t.Error("embedContent() didn't change after the embedded file's content changed")
+	}
+}
+
+func TestCopyEmbedsNoMatchIsError(t *testing.T) {
+	s := &sourceSet{
+		BaseDir: t.TempDir(),
+		Pragmas: pragmas{Embeds: []string{"nonexistent-*.txt"}},
+	}
+	if err := copyEmbeds(t.TempDir(), s); err == nil {
+		t.Fatal("copyEmbeds with a glob matching nothing = nil error, want error")
+	}
+}
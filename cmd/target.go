@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// buildTarget — GOOS/GOARCH/tags для одной сборки. По умолчанию совпадает
+// с хостом; -target позволяет собрать бинарник для другой платформы.
+type buildTarget struct {
+	GOOS   string
+	GOARCH string
+	Tags   string
+}
+
+// resolveTarget строит buildTarget из флагов -target/-tags, по умолчанию
+// нацеливаясь на хост.
+func resolveTarget(target, tags string) (buildTarget, error) {
+	t := buildTarget{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH, Tags: tags}
+
+	if target == "" {
+		return t, nil
+	}
+
+	goos, goarch, ok := strings.Cut(target, "/")
+	if !ok || goos == "" || goarch == "" {
+		return t, fmt.Errorf("invalid -target %q, want GOOS/GOARCH (e.g. linux/amd64)", target)
+	}
+
+	t.GOOS, t.GOARCH = goos, goarch
+	return t, nil
+}
+
+// isCrossCompile reports whether t targets a platform other than the host
+// gogo is running on, i.e. whether the produced binary can't just be exec'd.
+func (t buildTarget) isCrossCompile() bool {
+	return t.GOOS != runtime.GOOS || t.GOARCH != runtime.GOARCH
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestResolveTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		tags       string
+		wantGOOS   string
+		wantGOARCH string
+		wantErr    bool
+	}{
+		{
+			name:       "empty target defaults to host",
+			target:     "",
+			tags:       "netgo",
+			wantGOOS:   runtime.GOOS,
+			wantGOARCH: runtime.GOARCH,
+		},
+		{
+			name:       "explicit GOOS/GOARCH",
+			target:     "linux/arm64",
+			wantGOOS:   "linux",
+			wantGOARCH: "arm64",
+		},
+		{
+			name:    "missing slash",
+			target:  "linux",
+			wantErr: true,
+		},
+		{
+			name:    "missing GOARCH",
+			target:  "linux/",
+			wantErr: true,
+		},
+		{
+			name:    "missing GOOS",
+			target:  "/amd64",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveTarget(tt.target, tt.tags)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("resolveTarget(%q, %q) = nil error, want error", tt.target, tt.tags)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveTarget(%q, %q) unexpected error: %v", tt.target, tt.tags, err)
+			}
+			if got.GOOS != tt.wantGOOS || got.GOARCH != tt.wantGOARCH || got.Tags != tt.tags {
+				t.Errorf("resolveTarget(%q, %q) = %+v, want GOOS=%q GOARCH=%q Tags=%q",
+					tt.target, tt.tags, got, tt.wantGOOS, tt.wantGOARCH, tt.tags)
+			}
+		})
+	}
+}
+
+func TestIsCrossCompile(t *testing.T) {
+	tests := []struct {
+		name string
+		t    buildTarget
+		want bool
+	}{
+		{"matches host", buildTarget{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}, false},
+		{"different GOOS", buildTarget{GOOS: "plan9", GOARCH: runtime.GOARCH}, true},
+		{"different GOARCH", buildTarget{GOOS: runtime.GOOS, GOARCH: "riscv64"}, true},
+		{"both different", buildTarget{GOOS: "plan9", GOARCH: "riscv64"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.t.isCrossCompile(); got != tt.want {
+				t.Errorf("%+v.isCrossCompile() = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestModTimesEqual(t *testing.T) {
+	t0 := time.Now()
+	t1 := t0.Add(time.Second)
+
+	tests := []struct {
+		name string
+		a, b map[string]time.Time
+		want bool
+	}{
+		{"both empty", map[string]time.Time{}, map[string]time.Time{}, true},
+		{"identical", map[string]time.Time{"a": t0}, map[string]time.Time{"a": t0}, true},
+		{"different mtime", map[string]time.Time{"a": t0}, map[string]time.Time{"a": t1}, false},
+		{"different length", map[string]time.Time{"a": t0}, map[string]time.Time{"a": t0, "b": t0}, false},
+		{"different key", map[string]time.Time{"a": t0}, map[string]time.Time{"b": t0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := modTimesEqual(tt.a, tt.b); got != tt.want {
+				t.Errorf("modTimesEqual(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchTargets(t *testing.T) {
+	t.Run("no args is an error", func(t *testing.T) {
+		if _, err := watchTargets(nil); err == nil {
+			t.Fatal("watchTargets(nil) = nil error, want error (stdin can't be watched)")
+		}
+	})
+
+	t.Run("explicit files pass through unchanged", func(t *testing.T) {
+		args := []string{"a.go", "b.go"}
+		got, err := watchTargets(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || got[0] != "a.go" || got[1] != "b.go" {
+			t.Errorf("watchTargets(%v) = %v, want unchanged", args, got)
+		}
+	})
+
+	t.Run("single directory expands to its .go files", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, name := range []string{"a.go", "b.go", "c.txt"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("package main"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := watchTargets([]string{dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("watchTargets(%v) = %v, want 2 .go files", dir, got)
+		}
+	})
+}